@@ -0,0 +1,175 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// Cache stores and retrieves the fingerprint of the last successful install
+// of a package, so that GoShouldBuild can short-circuit to "no" whenever
+// nothing relevant has changed - regardless of file mtimes.
+type Cache interface {
+	// Get returns the fingerprint stored for name, and whether one was found.
+	Get(name string) (fingerprint string, ok bool)
+	// Put records fingerprint as the result of the last successful install of name.
+	Put(name, fingerprint string) error
+	// Reset discards every fingerprint the Cache knows about.
+	Reset() error
+}
+
+// dirCache is the default Cache, persisting fingerprints as files named
+// os.UserCacheDir()/yb/<name>.fingerprint (under os.TempDir() instead when
+// there is no user cache directory).
+type dirCache struct{ dir string }
+
+// newDirCache returns a dirCache rooted at os.UserCacheDir()/yb, creating it
+// if necessary.
+func newDirCache() *dirCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "yb")
+	_ = os.MkdirAll(dir, 0750)
+	return &dirCache{dir: dir}
+}
+
+func (c *dirCache) file(name string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(name)+".fingerprint")
+}
+
+func (c *dirCache) Get(name string) (string, bool) {
+	b, err := os.ReadFile(c.file(name))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+func (c *dirCache) Put(name, fingerprint string) error {
+	fn := c.file(name)
+	if err := os.MkdirAll(filepath.Dir(fn), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(fn, []byte(fingerprint), 0640)
+}
+
+func (c *dirCache) Reset() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return err
+	}
+	return os.MkdirAll(c.dir, 0750)
+}
+
+// Builder wraps the content-hash build cache used by GoInstall and
+// GoShouldBuild behind a pluggable Cache, so tests can supply an in-memory
+// implementation instead of touching os.UserCacheDir().
+type Builder struct {
+	Cache Cache
+}
+
+// defaultBuilder is the Builder used by the package-level GoInstall and
+// GoShouldBuild functions.
+var defaultBuilder = &Builder{Cache: newDirCache()}
+
+// ResetCache discards every fingerprint known to the default Builder's Cache.
+func ResetCache() {
+	_ = defaultBuilder.Cache.Reset()
+}
+
+// Fingerprint computes a content-addressed hash over name's *.go files, its
+// transitive first-party dependencies (as reported by GoDeps), the
+// go.mod/go.sum bytes, the resolved Go version, and the given tags/ldflags -
+// i.e. everything that should invalidate a cached install.
+func (b *Builder) Fingerprint(ctx context.Context, name, tags, ldflags string) (string, error) {
+	h := sha256.New()
+	seen := make(map[string]bool)
+	var walk func(pkg string) error
+	walk = func(pkg string) error {
+		if seen[pkg] {
+			return nil
+		}
+		seen[pkg] = true
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		files, err := filepath.Glob(filepath.Join(pkg, "*.go"))
+		if err != nil {
+			return err
+		}
+		for i, fn := range files {
+			files[i] = overlayResolve(fn)
+		}
+		for _, ext := range registeredExts() {
+			srcFiles, err := filepath.Glob(filepath.Join(pkg, "*"+ext))
+			if err != nil {
+				return err
+			}
+			files = append(files, srcFiles...)
+		}
+		sort.Strings(files)
+		for _, fn := range files {
+			fmt.Fprintln(h, fn)
+			f, err := os.Open(fn)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		for _, dep := range GoDeps(ctx, pkg) {
+			if err := walk(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(name); err != nil {
+		return "", fmt.Errorf("fingerprint %s: %w", name, err)
+	}
+	for _, fn := range []string{"go.mod", "go.sum"} {
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			continue
+		}
+		h.Write(b)
+	}
+	fmt.Fprintln(h, runtime.Version())
+	fmt.Fprintln(h, tags, ldflags)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheHit reports whether name's current fingerprint matches the one
+// recorded for its last successful install.
+func (b *Builder) cacheHit(ctx context.Context, name, tags, ldflags string) bool {
+	fp, err := b.Fingerprint(ctx, name, tags, ldflags)
+	if err != nil {
+		return false
+	}
+	cached, ok := b.Cache.Get(name)
+	return ok && cached == fp
+}
+
+// recordInstall stores name's current fingerprint as its last successful
+// install, so the next GoShouldBuild call can short-circuit to "no".
+func (b *Builder) recordInstall(ctx context.Context, name, tags, ldflags string) {
+	fp, err := b.Fingerprint(ctx, name, tags, ldflags)
+	if err != nil {
+		return
+	}
+	_ = b.Cache.Put(name, fp)
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memCache is an in-memory Cache for tests, per the request this Cache
+// interface was introduced to satisfy.
+type memCache struct{ m map[string]string }
+
+func newMemCache() *memCache { return &memCache{m: make(map[string]string)} }
+
+func (c *memCache) Get(name string) (string, bool) { fp, ok := c.m[name]; return fp, ok }
+func (c *memCache) Put(name, fingerprint string) error {
+	c.m[name] = fingerprint
+	return nil
+}
+func (c *memCache) Reset() error { c.m = make(map[string]string); return nil }
+
+func TestFingerprintChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Mkdir("pkg", 0750); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join("pkg", "main.go")
+	if err := os.WriteFile(src, []byte("package main\nfunc main() {}\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builder{Cache: newMemCache()}
+	ctx := context.Background()
+	fp1, err := b.Fingerprint(ctx, "pkg", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp2, err := b.Fingerprint(ctx, "pkg", "", ""); err != nil || fp2 != fp1 {
+		t.Fatalf("fingerprint is not stable for unchanged content: %q vs %q (err=%v)", fp1, fp2, err)
+	}
+
+	if err := os.WriteFile(src, []byte("package main\nfunc main() { println(1) }\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fp3, err := b.Fingerprint(ctx, "pkg", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp3 == fp1 {
+		t.Fatal("fingerprint did not change after source content changed")
+	}
+}
+
+func TestBuilderCacheHit(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.Mkdir("pkg", 0750); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join("pkg", "main.go")
+	if err := os.WriteFile(src, []byte("package main\nfunc main() {}\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builder{Cache: newMemCache()}
+	ctx := context.Background()
+	if b.cacheHit(ctx, "pkg", "", "") {
+		t.Fatal("cacheHit before any recordInstall should be false")
+	}
+
+	b.recordInstall(ctx, "pkg", "", "")
+	if !b.cacheHit(ctx, "pkg", "", "") {
+		t.Fatal("cacheHit should be true right after recordInstall with unchanged content")
+	}
+
+	if err := os.WriteFile(src, []byte("package main\nfunc main() { println(2) }\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if b.cacheHit(ctx, "pkg", "", "") {
+		t.Fatal("cacheHit should be false once source content changes")
+	}
+}
+
+func TestFingerprintResolvesOverlay(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer SetOverlay("")
+
+	if err := os.Mkdir("pkg", 0750); err != nil {
+		t.Fatal(err)
+	}
+	// The generated file's content lives outside pkg/, in scratch, and is
+	// the only thing the overlay points at; pkg/index_templ.go on disk is
+	// just a placeholder (written below).
+	scratch := filepath.Join(dir, "scratch-index_templ.go")
+	if err := os.WriteFile(scratch, []byte("package pkg\nvar V = 1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayPath, []byte(`{"Replace":{"pkg/index_templ.go":"`+scratch+`"}}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOverlay(overlayPath); err != nil {
+		t.Fatal(err)
+	}
+	// filepath.Glob("pkg/*.go") only sees real directory entries, so a
+	// placeholder must exist at the overlaid path for Fingerprint to find
+	// it at all; overlayResolve then swaps in scratch's content for it.
+	if err := os.WriteFile(filepath.Join("pkg", "index_templ.go"), []byte("package pkg\nvar V = 1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builder{Cache: newMemCache()}
+	ctx := context.Background()
+	fp1, err := b.Fingerprint(ctx, "pkg", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(scratch, []byte("package pkg\nvar V = 2\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fp2, err := b.Fingerprint(ctx, "pkg", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 == fp2 {
+		t.Fatal("Fingerprint did not change after the overlay-resolved generated file's content changed")
+	}
+}
+
+func TestResetCacheUsesCacheInterface(t *testing.T) {
+	mc := newMemCache()
+	mc.m["pkg"] = "deadbeef"
+	old := defaultBuilder.Cache
+	defaultBuilder.Cache = mc
+	defer func() { defaultBuilder.Cache = old }()
+
+	ResetCache()
+
+	if _, ok := mc.Get("pkg"); ok {
+		t.Fatal("ResetCache did not clear the in-memory Cache via its Reset method")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"strings"
+	"sync"
+)
+
+// Generator describes one code-generation tool yb knows how to bootstrap and
+// invoke. Ext is the source extension TemplateIsOld watches for (e.g.
+// ".qtpl"); Binary is the command to run; InstallPath is where to
+// "go install" it from when it's missing from PATH; Args are the arguments
+// passed when invoking Binary, run with the package directory given to
+// GoInstall as its working directory - an element equal to "{}" is replaced with the stale source
+// file's base name, for generators (like protoc) that must be told which
+// file to process rather than discovering it themselves; OutputSuffix (or
+// OutputSuffixes, for generators that produce more than one file per input)
+// is appended to the source path with Ext stripped, to get the generated
+// file(s) whose staleness is checked against the source.
+//
+// Generators without an Ext (e.g. stringer, mockgen, which are driven by
+// //go:generate directives rather than a distinct source extension) are not
+// picked up by TemplateIsOld's directory walk, but remain available via
+// GeneratorByBinary for callers that invoke them some other way.
+type Generator struct {
+	Ext            string
+	Binary         string
+	InstallPath    string
+	Args           []string
+	OutputSuffix   string
+	OutputSuffixes []string
+}
+
+// outputs returns every generated file path for the given source path.
+func (g Generator) outputs(path string) []string {
+	base := strings.TrimSuffix(path, g.Ext)
+	suffixes := g.OutputSuffixes
+	if len(suffixes) == 0 {
+		suffixes = []string{g.OutputSuffix}
+	}
+	outs := make([]string, 0, len(suffixes))
+	for _, s := range suffixes {
+		outs = append(outs, base+s)
+	}
+	return outs
+}
+
+var (
+	generatorsMu  sync.RWMutex
+	generatorsExt = make(map[string]Generator)
+	generatorsBin = make(map[string]Generator)
+)
+
+// RegisterGenerator adds (or replaces) a Generator in the registry consulted
+// by TemplateIsOld and GoInstall.
+func RegisterGenerator(g Generator) {
+	generatorsMu.Lock()
+	defer generatorsMu.Unlock()
+	if g.Ext != "" {
+		generatorsExt[g.Ext] = g
+	}
+	generatorsBin[g.Binary] = g
+}
+
+// registeredExts returns every Ext currently registered, for callers (like
+// Builder.Fingerprint) that need to hash generator source files alongside
+// *.go files.
+func registeredExts() []string {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	exts := make([]string, 0, len(generatorsExt))
+	for ext := range generatorsExt {
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// generatorForPath returns the registered Generator whose Ext matches path's
+// suffix - the longest match wins, so e.g. ".sqlc.yaml" beats a hypothetical
+// ".yaml" - and whether one exists.
+func generatorForPath(path string) (Generator, bool) {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	var best Generator
+	var found bool
+	for ext, g := range generatorsExt {
+		if strings.HasSuffix(path, ext) && (!found || len(ext) > len(best.Ext)) {
+			best, found = g, true
+		}
+	}
+	return best, found
+}
+
+// GeneratorByBinary returns the registered Generator for the given binary
+// name, and whether one exists.
+func GeneratorByBinary(binary string) (Generator, bool) {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	g, ok := generatorsBin[binary]
+	return g, ok
+}
+
+func init() {
+	RegisterGenerator(Generator{
+		Ext: ".qtpl", Binary: "qtc",
+		InstallPath:  "github.com/valyala/quicktemplate/qtc",
+		OutputSuffix: ".qtpl.go",
+	})
+	RegisterGenerator(Generator{
+		Ext: ".templ", Binary: "templ",
+		InstallPath:  "github.com/a-h/templ/cmd/templ",
+		Args:         []string{"generate"},
+		OutputSuffix: "_templ.go",
+	})
+	RegisterGenerator(Generator{
+		Ext: ".sqlc.yaml", Binary: "sqlc",
+		InstallPath:  "github.com/sqlc-dev/sqlc/cmd/sqlc",
+		Args:         []string{"generate"},
+		OutputSuffix: ".go",
+	})
+	RegisterGenerator(Generator{
+		// protoc-gen-go is a protoc *plugin* - it only speaks the
+		// CodeGeneratorRequest/Response protocol on stdin/stdout, so the
+		// compiler to actually invoke is protoc itself; "{}" is replaced
+		// with the stale .proto file's name. InstallPath installs only the
+		// protoc-gen-go plugin: GoInstall runs it when protoc is missing
+		// from PATH, and does not install protoc itself.
+		Ext: ".proto", Binary: "protoc",
+		InstallPath:  "google.golang.org/protobuf/cmd/protoc-gen-go",
+		Args:         []string{"--go_out=.", "--go_opt=paths=source_relative", "{}"},
+		OutputSuffix: ".pb.go",
+	})
+	RegisterGenerator(Generator{
+		Binary:      "stringer",
+		InstallPath: "golang.org/x/tools/cmd/stringer",
+	})
+	RegisterGenerator(Generator{
+		Binary:      "mockgen",
+		InstallPath: "go.uber.org/mock/mockgen",
+	})
+}
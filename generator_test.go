@@ -0,0 +1,46 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import "testing"
+
+func TestBuiltinGeneratorOutputs(t *testing.T) {
+	qtc, ok := generatorForPath("view/index.qtpl")
+	if !ok {
+		t.Fatal("qtc generator not found for .qtpl path")
+	}
+	if got, want := qtc.outputs("view/index.qtpl"), []string{"view/index.qtpl.go"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("qtc.outputs() = %v, want %v", got, want)
+	}
+
+	templ, ok := generatorForPath("view/index.templ")
+	if !ok {
+		t.Fatal("templ generator not found for .templ path")
+	}
+	if got, want := templ.outputs("view/index.templ"), []string{"view/index_templ.go"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("templ.outputs() = %v, want %v", got, want)
+	}
+}
+
+func TestGeneratorForPathLongestMatch(t *testing.T) {
+	RegisterGenerator(Generator{Ext: ".yaml", Binary: "fake-yaml-gen"})
+	g, ok := generatorForPath("db/queries.sqlc.yaml")
+	if !ok {
+		t.Fatal("no generator matched db/queries.sqlc.yaml")
+	}
+	if g.Binary != "sqlc" {
+		t.Fatalf("generatorForPath matched %q, want the longer .sqlc.yaml registration (sqlc)", g.Binary)
+	}
+}
+
+func TestGeneratorByBinary(t *testing.T) {
+	g, ok := GeneratorByBinary("stringer")
+	if !ok {
+		t.Fatal("stringer not registered")
+	}
+	if g.InstallPath == "" {
+		t.Fatal("stringer registration missing InstallPath")
+	}
+}
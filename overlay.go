@@ -0,0 +1,81 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// overlayFile is the on-disk JSON format accepted by SetOverlay - the same
+// {"Replace": {path: realPath}} shape "go build/install -overlay=..."
+// accepts; see cmd/go/internal/fsys.
+type overlayFile struct {
+	Replace map[string]string `json:"Replace"`
+}
+
+var (
+	overlayMu   sync.RWMutex
+	overlayPath string
+	overlayMap  map[string]string
+)
+
+// SetOverlay loads the JSON overlay file at path, so TemplateIsOld and
+// GoShouldBuild resolve generated .go files through it instead of stat-ing
+// them next to the .qtpl/.templ source - useful when sources live in a
+// read-only tree (Nix, Bazel sandboxes, CI caches) and generated files are
+// written to a scratch directory instead. GoShouldBuild and
+// Builder.Fingerprint resolve only the *.go files that exist in the package
+// directory; an overlay entry for a file absent there is not consulted.
+// Passing an empty path clears any previously set overlay.
+func SetOverlay(path string) error {
+	overlayMu.Lock()
+	defer overlayMu.Unlock()
+	if path == "" {
+		overlayPath, overlayMap = "", nil
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var of overlayFile
+	if err := json.Unmarshal(b, &of); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	overlayPath, overlayMap = path, of.Replace
+	return nil
+}
+
+// overlayResolve returns the real path backing name according to the
+// current overlay, or name unchanged if there is no overlay or no entry for it.
+func overlayResolve(name string) string {
+	overlayMu.RLock()
+	defer overlayMu.RUnlock()
+	if overlayMap == nil {
+		return name
+	}
+	if real, ok := overlayMap[name]; ok {
+		return real
+	}
+	return name
+}
+
+// WithOverlay runOption passes the overlay set via SetOverlay to
+// "go build"/"go install" via -overlay. It is a no-op if no overlay is set.
+func WithOverlay() runOption {
+	return func(cmd *exec.Cmd) {
+		overlayMu.RLock()
+		path := overlayPath
+		overlayMu.RUnlock()
+		if path == "" {
+			return
+		}
+		cmd.Args = append(cmd.Args, "-overlay="+path)
+	}
+}
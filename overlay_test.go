@@ -0,0 +1,46 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetOverlayResolve(t *testing.T) {
+	defer SetOverlay("")
+
+	if got := overlayResolve("view/index.qtpl.go"); got != "view/index.qtpl.go" {
+		t.Fatalf("overlayResolve with no overlay set should be a no-op, got %q", got)
+	}
+
+	dir := t.TempDir()
+	real := filepath.Join(dir, "index.qtpl.go")
+	overlay := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlay, []byte(`{"Replace":{"view/index.qtpl.go":"`+real+`"}}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetOverlay(overlay); err != nil {
+		t.Fatal(err)
+	}
+	if got := overlayResolve("view/index.qtpl.go"); got != real {
+		t.Fatalf("overlayResolve() = %q, want %q", got, real)
+	}
+	if got := overlayResolve("view/other.go"); got != "view/other.go" {
+		t.Fatalf("overlayResolve() for an unmapped path should pass through unchanged, got %q", got)
+	}
+}
+
+func TestWithOverlayNoopWithoutOverlaySet(t *testing.T) {
+	SetOverlay("")
+	cmd := exec.Command("go", "install")
+	WithOverlay()(cmd)
+	if len(cmd.Args) != 2 {
+		t.Fatalf("WithOverlay() should not modify Args when no overlay is set, got %v", cmd.Args)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// installNode tracks one name's place in the InstallAll dependency DAG: ctx
+// is derived from the parent context so a failed dependency can cancel it
+// (and, transitively, every node downstream of it) without touching
+// unrelated siblings; done is closed once the node has finished (or been
+// skipped) and err records the outcome.
+type installNode struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	err    error
+}
+
+// installFunc and depsFunc are indirections over GoInstall and GoDeps so
+// tests can exercise InstallAll's scheduling and cancellation semantics
+// without shelling out to the real go toolchain.
+var (
+	installFunc = GoInstall
+	depsFunc    = GoDeps
+)
+
+// safeDeps calls depsFunc, recovering any panic (GoDeps panics on a
+// build.ImportDir error, e.g. a package whose code-gen hasn't run yet and so
+// has no *.go files) and turning it into an error - so one bad node fails on
+// its own instead of taking down the whole InstallAll call.
+func safeDeps(ctx context.Context, name string) (deps []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("GoDeps(%s): %v", name, r)
+		}
+	}()
+	return depsFunc(ctx, name), nil
+}
+
+// InstallAll builds a dependency DAG over names using GoDeps (restricted to
+// names - i.e. local, first-party sibling subpackages), topologically
+// schedules them, and runs GoInstall for each in parallel up to concurrency,
+// re-using the content-hash cache so unchanged leaves are skipped. Progress
+// and errors stream through LoggerFromContext(ctx). A failed node cancels
+// only its downstream dependents, via a context derived from ctx - unrelated
+// siblings keep building. InstallAll returns the first error encountered, if
+// any.
+func InstallAll(ctx context.Context, names []string, concurrency int) error {
+	logger := LoggerFromContext(ctx)
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	nodes := make(map[string]*installNode, len(names))
+	for _, name := range names {
+		nctx, cancel := context.WithCancelCause(ctx)
+		nodes[name] = &installNode{ctx: nctx, cancel: cancel, done: make(chan struct{})}
+	}
+
+	dependents := make(map[string][]string, len(names))
+	deps := make(map[string][]string, len(names))
+	depErrs := make(map[string]error, len(names))
+	for _, name := range names {
+		ds, err := safeDeps(ctx, name)
+		if err != nil {
+			depErrs[name] = err
+			continue
+		}
+		deps[name] = ds
+		for _, dep := range ds {
+			if _, ok := nodes[dep]; ok {
+				dependents[dep] = append(dependents[dep], name)
+			}
+		}
+	}
+
+	var cascadeCancel func(name string, cause error)
+	cascadeCancel = func(name string, cause error) {
+		for _, dep := range dependents[name] {
+			nodes[dep].cancel(cause)
+			cascadeCancel(dep, cause)
+		}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for _, name := range names {
+		name, n := name, nodes[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(n.done)
+
+			if err := depErrs[name]; err != nil {
+				n.err = err
+				logger.Error("deps", "name", name, "error", err)
+				n.cancel(err)
+				cascadeCancel(name, err)
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", name, err)
+				}
+				mu.Unlock()
+				return
+			}
+
+			for _, dep := range deps[name] {
+				dn, ok := nodes[dep]
+				if !ok {
+					continue
+				}
+				<-dn.done
+			}
+			if cause := context.Cause(n.ctx); cause != nil {
+				n.err = cause
+				logger.Log("skip", "name", name, "error", cause)
+				return
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			_, err := installFunc(n.ctx, name, false)
+			n.err = err
+			logger.Log("installed", "name", name, "error", err)
+			if err != nil {
+				wrapped := fmt.Errorf("%s: %w", name, err)
+				n.cancel(wrapped)
+				cascadeCancel(name, wrapped)
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = wrapped
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// withFakeInstall stubs installFunc/depsFunc for the duration of fn, so
+// InstallAll's scheduling and cancellation semantics can be exercised
+// without shelling out to the real go toolchain.
+func withFakeInstall(t *testing.T, deps map[string][]string, install func(name string) error, fn func()) {
+	t.Helper()
+	oldInstall, oldDeps := installFunc, depsFunc
+	installFunc = func(ctx context.Context, name string, force bool) (bool, error) {
+		return true, install(name)
+	}
+	depsFunc = func(ctx context.Context, name string) []string { return deps[name] }
+	defer func() { installFunc, depsFunc = oldInstall, oldDeps }()
+	fn()
+}
+
+func TestInstallAllRunsInDependencyOrder(t *testing.T) {
+	deps := map[string][]string{
+		"a": nil,
+		"b": {"a"},
+		"c": {"b"},
+	}
+	var mu sync.Mutex
+	var order []string
+	install := func(name string) error {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		return nil
+	}
+
+	withFakeInstall(t, deps, install, func() {
+		if err := InstallAll(context.Background(), []string{"a", "b", "c"}, 4); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	pos := map[string]int{}
+	for i, n := range order {
+		pos[n] = i
+	}
+	if pos["a"] > pos["b"] || pos["b"] > pos["c"] {
+		t.Fatalf("dependency order violated: %v", order)
+	}
+}
+
+func TestInstallAllCancelsOnlyDownstream(t *testing.T) {
+	// b depends on a, c is independent of both.
+	deps := map[string][]string{
+		"a": nil,
+		"b": {"a"},
+		"c": nil,
+	}
+	var mu sync.Mutex
+	ran := map[string]bool{}
+	wantErr := errors.New("boom")
+	install := func(name string) error {
+		mu.Lock()
+		ran[name] = true
+		mu.Unlock()
+		if name == "a" {
+			return wantErr
+		}
+		return nil
+	}
+
+	var err error
+	withFakeInstall(t, deps, install, func() {
+		err = InstallAll(context.Background(), []string{"a", "b", "c"}, 4)
+	})
+
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("InstallAll() error = %v, want wrapping %v", err, wantErr)
+	}
+	if ran["b"] {
+		t.Fatal("b depends on the failed node a and should have been skipped")
+	}
+	if !ran["c"] {
+		t.Fatal("c is independent of the failed node a and should still have run")
+	}
+}
+
+func TestInstallAllSurvivesDepsFuncPanic(t *testing.T) {
+	// b's deps computation panics (as GoDeps does on a build.ImportDir
+	// error); c is unrelated and should still install fine.
+	deps := map[string][]string{
+		"a": nil,
+		"c": nil,
+	}
+	var mu sync.Mutex
+	ran := map[string]bool{}
+	install := func(name string) error {
+		mu.Lock()
+		ran[name] = true
+		mu.Unlock()
+		return nil
+	}
+
+	oldInstall, oldDeps := installFunc, depsFunc
+	installFunc = func(ctx context.Context, name string, force bool) (bool, error) {
+		return true, install(name)
+	}
+	depsFunc = func(ctx context.Context, name string) []string {
+		if name == "b" {
+			panic("no Go files yet")
+		}
+		return deps[name]
+	}
+	defer func() { installFunc, depsFunc = oldInstall, oldDeps }()
+
+	err := InstallAll(context.Background(), []string{"a", "b", "c"}, 4)
+	if err == nil {
+		t.Fatal("InstallAll() error = nil, want an error for b's panicking deps computation")
+	}
+	if ran["b"] {
+		t.Fatal("b's deps computation panicked and should not have been installed")
+	}
+	if !ran["a"] || !ran["c"] {
+		t.Fatal("a and c are unrelated to b's panic and should still have run")
+	}
+}
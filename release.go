@@ -0,0 +1,305 @@
+// Copyright 2024 Tamas Gulacsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yb
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/goyek/goyek/v2"
+)
+
+// Target describes one GOOS/GOARCH combination (plus build tags/ldflags) to
+// build as part of a release.
+type Target struct {
+	GOOS, GOARCH string
+	Tags         string
+	LDFlags      string
+}
+
+// String returns the target in the "GOOS-GOARCH" form used for dist/ paths.
+func (t Target) String() string { return t.GOOS + "-" + t.GOARCH }
+
+// BuildMatrix is a release's full set of inputs: the package to build, the
+// Targets to build it for, and any auxiliary files (README, LICENSE, config
+// samples, ...) to bundle alongside the binary in every archive.
+type BuildMatrix struct {
+	Name       string
+	Targets    []Target
+	Version    string // derived from "git describe" when empty
+	VersionVar string // "importpath.Var" to set via -ldflags -X; skipped when empty
+	AuxFiles   []string
+	DistDir    string // defaults to "dist"
+	Deb        bool   // also emit a .deb for linux targets
+}
+
+// ReleaseAll builds Name for every Target in m, and bundles each result
+// (together with m.AuxFiles) into a tar.gz and a zip under m.DistDir - and,
+// if m.Deb is set, a minimal .deb package for linux targets. It is meant to
+// be used as a goyek.Task Action:
+//
+//	Define(Task{Name: "release", Action: ReleaseAll(matrix)})
+func ReleaseAll(m BuildMatrix) func(a *goyek.A) {
+	return func(a *goyek.A) {
+		a.Helper()
+		ctx := a.Context()
+		dist := m.DistDir
+		if dist == "" {
+			dist = "dist"
+		}
+		version := m.Version
+		if version == "" {
+			v, err := gitDescribe(ctx)
+			if err != nil {
+				a.Logf("git describe: %v", err)
+				v = "dev"
+			}
+			version = v
+		}
+		for _, t := range m.Targets {
+			if err := releaseOne(ctx, m, t, dist, version); err != nil {
+				a.Errorf("%s: %v", t, err)
+			}
+		}
+	}
+}
+
+// versionLDFlags returns the "-X importpath.Var=version" ldflag for m, or
+// empty when m.VersionVar isn't set - injecting a version string only makes
+// sense when the caller tells us which package variable to set it on.
+func versionLDFlags(m BuildMatrix, version string) string {
+	if m.VersionVar == "" {
+		return ""
+	}
+	return fmt.Sprintf("-X %s=%s", m.VersionVar, version)
+}
+
+func gitDescribe(ctx context.Context) (string, error) {
+	b, err := exec.CommandContext(ctx, "git", "describe", "--tags", "--always", "--dirty").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func releaseOne(ctx context.Context, m BuildMatrix, t Target, dist, version string) error {
+	workDir := filepath.Join(dist, "work", t.String())
+	if err := os.MkdirAll(workDir, 0750); err != nil {
+		return err
+	}
+	binName := m.Name
+	if t.GOOS == "windows" {
+		binName += ".exe"
+	}
+	binPath := filepath.Join(workDir, binName)
+	ldflags := strings.TrimSpace(strings.Join([]string{"-s -w", versionLDFlags(m, version), t.LDFlags}, " "))
+	cmd := exec.CommandContext(ctx, "go", "build", "-tags="+t.Tags, "-ldflags", ldflags, "-o", binPath, "./"+m.Name)
+	WithEnv("GOOS="+t.GOOS, "GOARCH="+t.GOARCH)(cmd)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w", string(b), err)
+	}
+
+	files := append([]string{binPath}, m.AuxFiles...)
+	base := fmt.Sprintf("%s_%s_%s", m.Name, version, t)
+
+	if err := writeTarGz(filepath.Join(dist, base+".tar.gz"), files); err != nil {
+		return fmt.Errorf("tar.gz: %w", err)
+	}
+	if err := writeZip(filepath.Join(dist, base+".zip"), files); err != nil {
+		return fmt.Errorf("zip: %w", err)
+	}
+	if m.Deb && t.GOOS == "linux" {
+		if err := writeDeb(filepath.Join(dist, base+".deb"), m.Name, version, t.GOARCH, binPath); err != nil {
+			return fmt.Errorf("deb: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeTarGz(dest string, files []string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, fn := range files {
+		if err := addToTar(tw, fn); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func addToTar(tw *tar.Writer, fn string) error {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.Base(fn)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func writeZip(dest string, files []string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, fn := range files {
+		if err := addToZip(zw, fn); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func addToZip(zw *zip.Writer, fn string) error {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return err
+	}
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.Base(fn)
+	hdr.Method = zip.Deflate
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// debArch maps a GOARCH to the architecture name Debian's control file and
+// package filename expect. GOARCH values with no well-known Debian
+// equivalent are passed through unchanged.
+var debArch = map[string]string{
+	"amd64": "amd64",
+	"386":   "i386",
+	"arm64": "arm64",
+	"arm":   "armhf",
+}
+
+// writeDeb writes a minimal .deb package (an ar(1) archive of debian-binary,
+// control.tar.gz and data.tar.gz) installing bin at /usr/bin/<name>. It
+// skips maintainer scripts and dependency declarations - just enough to
+// produce a package dpkg will install.
+func writeDeb(dest, name, version, goarch, bin string) error {
+	content, err := os.ReadFile(bin)
+	if err != nil {
+		return err
+	}
+	dataBuf, err := tarGzBytes(map[string]tarFile{"./usr/bin/" + name: {Content: content, Mode: 0755}})
+	if err != nil {
+		return err
+	}
+	arch, ok := debArch[goarch]
+	if !ok {
+		arch = goarch
+	}
+	control := fmt.Sprintf("Package: %s\nVersion: %s\nArchitecture: %s\nMaintainer: unknown\nDescription: %s\n",
+		name, strings.TrimPrefix(version, "v"), arch, name)
+	controlBuf, err := tarGzBytes(map[string]tarFile{"./control": {Content: []byte(control), Mode: 0644}})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.WriteString(f, "!<arch>\n"); err != nil {
+		return err
+	}
+	if err := writeArEntry(f, "debian-binary", []byte("2.0\n")); err != nil {
+		return err
+	}
+	if err := writeArEntry(f, "control.tar.gz", controlBuf); err != nil {
+		return err
+	}
+	return writeArEntry(f, "data.tar.gz", dataBuf)
+}
+
+// tarFile is one entry for tarGzBytes: its content plus the Unix file mode
+// it should be written with (e.g. 0755 for an installed binary, 0644 for a
+// plain text control file).
+type tarFile struct {
+	Content []byte
+	Mode    int64
+}
+
+func tarGzBytes(files map[string]tarFile) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, f := range files {
+		hdr := &tar.Header{Name: name, Mode: f.Mode, Size: int64(len(f.Content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeArEntry writes one ar(1) entry - the format .deb archives are built from.
+func writeArEntry(w io.Writer, name string, content []byte) error {
+	hdr := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8s%-10d`\n", name, 0, 0, 0, "100644", len(content))
+	if _, err := io.WriteString(w, hdr); err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		return err
+	}
+	if len(content)%2 == 1 {
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return nil
+}
@@ -86,23 +86,17 @@ func ResetInstalled() { installedMu.Lock(); clear(installed); installedMu.Unlock
 // GoInstall go install the given name.
 func GoInstall(ctx context.Context, name string, force bool) (bool, error) {
 	logger := LoggerFromContext(ctx)
-	if gen, err := TemplateIsOld(ctx, name, force); err != nil {
+	if gen, genPath, err := TemplateIsOld(ctx, name, force); err != nil {
 		logger.Log("template", "error", err)
 		return true, err
-	} else if gen != "" {
+	} else if gen.Ext != "" {
 		var buf strings.Builder
-		if _, err := exec.LookPath(gen); err != nil {
-			logger.Log("lookPath", "gen", gen, "error", err)
-			var from string
-			switch gen {
-			case "qtc":
-				from = "github.com/valyala/quicktemplate/qtc"
-			case "templ":
-				from = "github.com/a-h/templ/cmd/templ"
-			default:
+		if _, err := exec.LookPath(gen.Binary); err != nil {
+			logger.Log("lookPath", "gen", gen.Binary, "error", err)
+			if gen.InstallPath == "" {
 				return true, err
 			}
-			cmd := exec.CommandContext(ctx, "go", "install", from+"@latest")
+			cmd := exec.CommandContext(ctx, "go", "install", gen.InstallPath+"@latest")
 			cmd.Stdout, cmd.Stderr = io.MultiWriter(os.Stdout, &buf), io.MultiWriter(os.Stderr, &buf)
 			logger.Log("run", "cmd", cmd.Args)
 			if err := cmd.Run(); err != nil {
@@ -111,11 +105,14 @@ func GoInstall(ctx context.Context, name string, force bool) (bool, error) {
 			}
 		}
 
-		args := []string{""}[:0]
-		if gen == "templ" {
-			args = append(args, "generate")
+		args := make([]string, len(gen.Args))
+		for i, a := range gen.Args {
+			if a == "{}" {
+				a = filepath.Base(genPath)
+			}
+			args[i] = a
 		}
-		cmd := exec.CommandContext(ctx, gen, args...)
+		cmd := exec.CommandContext(ctx, gen.Binary, args...)
 		cmd.Dir = name
 		buf.Reset()
 		cmd.Stdout, cmd.Stderr = io.MultiWriter(os.Stdout, &buf), io.MultiWriter(os.Stderr, &buf)
@@ -126,13 +123,16 @@ func GoInstall(ctx context.Context, name string, force bool) (bool, error) {
 		}
 	}
 	if force || GoShouldBuild(ctx, name) {
-		cmd := exec.CommandContext(ctx, "go", "install", "-ldflags=-s -w", "-tags="+brunoCus, "./"+name)
+		cmd := exec.CommandContext(ctx, "go", "install", "-ldflags=-s -w", "-tags="+brunoCus)
+		WithOverlay()(cmd)
+		cmd.Args = append(cmd.Args, "./"+name)
 		if b, err := cmd.CombinedOutput(); err != nil {
 			return true, fmt.Errorf("%s: %w", string(b), err)
 		}
 		installedMu.Lock()
 		installed[name] = struct{}{}
 		installedMu.Unlock()
+		defaultBuilder.recordInstall(ctx, name, brunoCus, "-s -w")
 		return true, nil
 	}
 	return false, nil
@@ -166,11 +166,11 @@ func MTime(paths ...string) int64 {
 func GoShouldBuild(ctx context.Context, name string) bool {
 	logger := LoggerFromContext(ctx)
 	logger.Log("GoShouldBuild", "name", name)
-	if gen, err := TemplateIsOld(ctx, name, false); err != nil {
+	if gen, _, err := TemplateIsOld(ctx, name, false); err != nil {
 		logger.Error("QtcIsOld", "error", err)
 		return true
-	} else if gen != "" {
-		logger.Log("template is old", "gen", gen)
+	} else if gen.Ext != "" {
+		logger.Log("template is old", "gen", gen.Binary)
 		return true
 	}
 	var pkg *build.Package
@@ -185,6 +185,12 @@ func GoShouldBuild(ctx context.Context, name string) bool {
 		if pkg.IsCommand() {
 			return true
 		}
+	} else if defaultBuilder.cacheHit(ctx, name, brunoCus, "-s -w") {
+		// Only trust the content-hash cache once we've confirmed the
+		// destination binary still exists - otherwise a wiped GOBIN or
+		// fresh checkout with a stale cache dir would never rebuild.
+		logger.Log("cache hit", "name", name)
+		return false
 	}
 	goModTime := MTime("go.mod")
 	if destTime != 0 && destTime < goModTime {
@@ -192,6 +198,9 @@ func GoShouldBuild(ctx context.Context, name string) bool {
 		return true
 	}
 	files, _ := filepath.Glob(filepath.Join(name, "*.go"))
+	for i, fn := range files {
+		files[i] = overlayResolve(fn)
+	}
 	maxTime := MTime(files...)
 	if destTime != 0 && destTime < maxTime {
 		logger.Log("*.go is newer than dest")
@@ -201,10 +210,15 @@ func GoShouldBuild(ctx context.Context, name string) bool {
 	return false
 }
 
-// TemplateIsOldreports whether the given directory needs qtc/templ to be run.
-func TemplateIsOld(ctx context.Context, root string, force bool) (string, error) {
+// TemplateIsOld reports whether the given directory needs one of the
+// registered Generators to be run, by matching files against every
+// registered Generator.Ext. When it finds a stale source, it also returns
+// that source's path, so callers (GoInstall) can pass it on to generators -
+// like protoc - that need to be told which file to process.
+func TemplateIsOld(ctx context.Context, root string, force bool) (Generator, string, error) {
 	logger := LoggerFromContext(ctx)
-	var gen string
+	var gen Generator
+	var genPath string
 	err := filepath.WalkDir(root, func(path string, di fs.DirEntry, err error) error {
 		if err := ctx.Err(); err != nil {
 			return err
@@ -213,26 +227,30 @@ func TemplateIsOld(ctx context.Context, root string, force bool) (string, error)
 			logger.Error("walk", "path", path, "error", err)
 			return nil
 		}
-		if gen != "" {
+		if gen.Ext != "" {
 			return fs.SkipAll
 		}
 		if di.Type().IsRegular() {
-			if ext := filepath.Ext(path); ext == ".qtpl" || ext == ".templ" {
+			if g, ok := generatorForPath(path); ok {
 				fi, err := di.Info()
 				if err != nil {
 					logger.Error("stat", "file", di.Name(), "error", err)
 					return err
 				}
-				if force || fi.ModTime().UnixMilli() > MTime(path+".go") {
-					gen = ext[1:]
-					logger.Log("go is older than ", "gen", gen, "path", path)
+				outs := g.outputs(path)
+				for i, o := range outs {
+					outs[i] = overlayResolve(o)
+				}
+				if force || fi.ModTime().UnixMilli() > MTime(outs...) {
+					gen, genPath = g, path
+					logger.Log("go is older than ", "gen", gen.Binary, "path", path)
 					return fs.SkipAll
 				}
 			}
 		}
 		return nil
 	})
-	return gen, err
+	return gen, genPath, err
 }
 
 // Run an external program reporting on a.
@@ -253,6 +271,17 @@ type runOption func(*exec.Cmd)
 // AtDir runOption sets cmd.Dir.
 func AtDir(dir string) runOption { return func(cmd *exec.Cmd) { cmd.Dir = dir } }
 
+// WithEnv runOption appends the given "key=value" pairs to cmd.Env, which is
+// seeded from os.Environ() if not already set.
+func WithEnv(env ...string) runOption {
+	return func(cmd *exec.Cmd) {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
+}
+
 // ReadDirLinks reads the links contained at path dir.
 func ReadDirLinks(path string) ([]string, error) {
 	dis, err := os.ReadDir(path)